@@ -0,0 +1,70 @@
+// CHECKSUM.GO
+// Verifies a downloaded file against an expected digest, so a
+// corrupted or tampered transfer is caught instead of silently
+// written to disk.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package fasthttp
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"strings"
+)
+
+// newHasher returns a fresh hash.Hash for one of the algorithms
+// Expected supports.
+func newHasher(algorithm string) (hash.Hash, error) {
+	switch strings.ToLower(algorithm) {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("fasthttp: unsupported checksum algorithm %q", algorithm)
+	}
+}
+
+// verifyDigest re-reads the file at path and compares its digest
+// against expected, returning an error on a mismatch (or if the
+// algorithm isn't recognized).
+//
+// This is the simple approach: one more pass over the finished file,
+// rather than combining per-chunk partial hash states in offset
+// order as the chunks land. It costs an extra read of the file, but
+// avoids the bookkeeping (and the algorithm-specific state-merging
+// math) that incremental hashing across concurrent, out-of-order
+// writers would require.
+func verifyDigest(path string, expected *Expected) error {
+	h, err := newHasher(expected.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(h, f); err != nil {
+		return err
+	}
+
+	got := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(got, expected.Digest) {
+		return fmt.Errorf("fasthttp: checksum mismatch for %q: expected %s %s, got %s", path, expected.Algorithm, expected.Digest, got)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+// CLIENT_TEST.GO
+// Covers Client's retry behavior and the header it adds to every
+// request, and that GetReader's unranged fallback actually goes
+// through a configured Client instead of http.DefaultClient.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package fasthttp
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestClientRetriesOn5xx checks that Client.do retries a request that
+// comes back with a 5xx status, succeeding once the server recovers.
+func TestClientRetriesOn5xx(t *testing.T) {
+	var attempts int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	req, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatalf("NewRequest: %v", err)
+	}
+
+	c := &Client{RetryBackoff: time.Millisecond}
+	resp, err := c.do(req)
+	if err != nil {
+		t.Fatalf("do: %v", err)
+	}
+	resp.Body.Close()
+
+	if got := atomic.LoadInt32(&attempts); got != 3 {
+		t.Fatalf("server saw %d attempts, want 3", got)
+	}
+}
+
+// TestGetReaderFallbackUsesConfiguredClient checks that GetReader's
+// length == 0 fallback path (no content length / no range support)
+// sends a configured Client's headers, instead of going around it
+// through http.DefaultClient.
+func TestGetReaderFallbackUsesConfiguredClient(t *testing.T) {
+	body := []byte("streamed without any ranging")
+
+	var gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			// No Accept-Ranges, no Content-Length: headInfo reports
+			// Length == 0, forcing the unranged fallback path.
+			return
+		}
+		gotHeader = r.Header.Get("X-Test-Header")
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	u := mustParseURL(t, srv.URL)
+	opts := &Options{
+		Client: &Client{Headers: http.Header{"X-Test-Header": []string{"present"}}},
+	}
+
+	r, err := GetReader(u, opts)
+	if err != nil {
+		t.Fatalf("GetReader: %v", err)
+	}
+	defer r.Close()
+
+	buf := make([]byte, len(body))
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+
+	if gotHeader != "present" {
+		t.Fatalf("server saw X-Test-Header %q, want %q", gotHeader, "present")
+	}
+}
@@ -12,7 +12,7 @@ import (
 	"log"
 	"os"
 	"net/url"
-	
+
 	"github.com/jameswelch2-bydeluxe/fasthttp"
 )
 
@@ -21,46 +21,121 @@ func main() {
 	const (
 		threadDefault	=	1
 		threadUsage		=	"number of threads to use (positive integer < 256)"
+		manifestUsage	=	"path to a manifest (JSON or text) of files to download instead of a single URL"
+		maxFilesDefault	=	fasthttp.DefaultMaxConcurrentFiles
+		maxFilesUsage	=	"max number of manifest files to download at once"
 	)
 	var threads uint64
 	flag.Uint64Var(&threads, "threads", threadDefault, threadUsage)
 	flag.Uint64Var(&threads, "t", threadDefault, threadUsage)
+	var manifest string
+	flag.StringVar(&manifest, "manifest", "", manifestUsage)
+	var maxFiles int
+	flag.IntVar(&maxFiles, "max-files", maxFilesDefault, maxFilesUsage)
 	flag.Parse()
 
-	// Do we have the correct number of arguments?
-	args := flag.Args()
-	if (len(args) < 1) || (len(args) > 2) {
-		log.Fatalln("Expected two args: URL and file path.")
-	}
-	
 	// Is the thread count valid?
 	if threads > 255 {
 		log.Fatalf("Invalid thread count: %d is not a byte value.\n", threads)
 	}
-	
+
+	if manifest != "" {
+		runManifest(manifest, maxFiles)
+		return
+	}
+
+	// Do we have the correct number of arguments?
+	args := flag.Args()
+	if (len(args) < 1) || (len(args) > 2) {
+		log.Fatalln("Expected two args: URL and file path (or -manifest).")
+	}
+
 	// The first argument should be a valid URL
 	u, err := url.Parse(args[0])
 	if err != nil {
 		log.Fatalf("\"%s\" is not a valid URL.\n", args[0])
 	}
-	
+
+	// Default to a simple terminal progress bar, driven entirely off
+	// the library's callback stream.
+	opts := progressOptions()
+
 	// Okay, now actually make the call to the library
+	var result fasthttp.Result
 	if len(args) == 1 {
 		// No output path specified, so let's use binary mode, and dump
 		// to standard out.
 		var bytes []byte
-		bytes, err = fasthttp.Get(u, byte(threads))
+		bytes, result, err = fasthttp.Get(u, byte(threads), opts)
 		fmt.Printf("%s\n", bytes)
 	} else {
 		// Output pth is prsent, so let's use file mode.
-		err = fasthttp.Save(u, args[1], byte(threads))
+		result, err = fasthttp.Save(u, args[1], byte(threads), opts)
 	}
 
 	if err != nil {
 		log.Fatalln(err)
 	}
-	
+
 	// We're done.
-	log.Println("Success!")
+	fmt.Println()
+	var fetched int64
+	for _, n := range result.WorkerBytes {
+		fetched += n
+	}
+	log.Printf("Success! Fetched %d bytes in %s.\n", fetched, result.Elapsed)
+	os.Exit(0)
+}
+
+// runManifest loads a manifest of files from path and downloads all
+// of them through a Downloader, bounded to maxFiles files at once.
+func runManifest(path string, maxFiles int) {
+	entries, err := fasthttp.LoadManifest(path)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	log.Printf("Downloading %d file(s) from manifest %q, %d at a time...\n", len(entries), path, maxFiles)
+
+	d := &fasthttp.Downloader{MaxConcurrentFiles: maxFiles}
+	results, err := d.Download(entries)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	var total int64
+	for _, r := range results {
+		for _, n := range r.WorkerBytes {
+			total += n
+		}
+	}
+	log.Printf("Success! Fetched %d file(s), %d bytes total.\n", len(results), total)
 	os.Exit(0)
-}
\ No newline at end of file
+}
+
+// progressOptions builds the default progress bar shown while the CLI
+// is downloading: one line, overwritten in place, reporting the
+// running total across all workers.
+func progressOptions() *fasthttp.Options {
+	var total int64
+	var written int64
+
+	return &fasthttp.Options{
+		OnBeforeStart: func(totalSize int64, workers int64) {
+			total = totalSize
+			if total > 0 {
+				log.Printf("Downloading %d bytes across %d worker(s)...\n", total, workers)
+			} else {
+				log.Printf("Downloading across %d worker(s)...\n", workers)
+			}
+		},
+		OnProgress: func(workerID int, bytesWritten int64) {
+			written += bytesWritten
+			if total > 0 {
+				fmt.Printf("\r%d / %d bytes (%.1f%%)", written, total, 100*float64(written)/float64(total))
+			} else {
+				fmt.Printf("\r%d bytes", written)
+			}
+		},
+	}
+}
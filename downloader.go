@@ -0,0 +1,196 @@
+// DOWNLOADER.GO
+// Provides a multi-file downloader on top of the single-resource
+// Get/Save functions, subject to a global cap on both how many files
+// are in flight and how many HTTP requests are in flight across all
+// of them.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package fasthttp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultMaxConcurrentFiles is how many files a Downloader will
+	// save at once, if MaxConcurrentFiles isn't set.
+	DefaultMaxConcurrentFiles = 4
+
+	// DefaultDownloaderMaxConcurrency is how many GET requests a
+	// Downloader will allow in flight across all of its files at
+	// once, if MaxConcurrency isn't set. The HEAD request each file
+	// starts with isn't counted.
+	DefaultDownloaderMaxConcurrency = 16
+)
+
+// ManifestEntry describes one file a Downloader should fetch: where
+// to get it from, and where to put it. Threads is optional; zero
+// means "use one thread for this file."
+type ManifestEntry struct {
+	URL     string `json:"url"`
+	Dest    string `json:"destPath"`
+	Threads byte   `json:"threads,omitempty"`
+}
+
+// Downloader fetches a manifest of files concurrently, subject to two
+// independent caps: how many files can be saving at once
+// (MaxConcurrentFiles), and how many HTTP requests can be in flight
+// across all of those files at once (MaxConcurrency). The latter
+// exists because each file may itself be split across several
+// threads; without a shared cap, a manifest of large, many-threaded
+// files could still open an unbounded number of connections.
+type Downloader struct {
+	// MaxConcurrentFiles bounds how many manifest entries are being
+	// saved at once. Zero means DefaultMaxConcurrentFiles.
+	MaxConcurrentFiles int
+
+	// MaxConcurrency bounds how many GET requests, across every file
+	// in the manifest, are in flight at once; the HEAD request each
+	// file starts with isn't counted. Zero means
+	// DefaultDownloaderMaxConcurrency.
+	MaxConcurrency int
+
+	// Options, if set, is used for every file in the manifest (its
+	// progress callbacks will report per-worker, per-file progress
+	// the same way they would for a standalone Save call).
+	Options *Options
+}
+
+func (d *Downloader) maxConcurrentFiles() int {
+	if d == nil || d.MaxConcurrentFiles <= 0 {
+		return DefaultMaxConcurrentFiles
+	}
+	return d.MaxConcurrentFiles
+}
+
+func (d *Downloader) maxConcurrency() int {
+	if d == nil || d.MaxConcurrency <= 0 {
+		return DefaultDownloaderMaxConcurrency
+	}
+	return d.MaxConcurrency
+}
+
+// Download fetches every entry in the manifest and returns each
+// entry's Result, in manifest order. If any entry fails, the rest
+// already in flight are allowed to finish, but no new ones are
+// started, and the first error encountered is returned.
+func (d *Downloader) Download(entries []ManifestEntry) ([]Result, error) {
+	return d.DownloadWithContext(context.Background(), entries)
+}
+
+// DownloadWithContext behaves like Download, but aborts as soon as
+// ctx is cancelled.
+func (d *Downloader) DownloadWithContext(ctx context.Context, entries []ManifestEntry) ([]Result, error) {
+	// Clone the caller's Options (if any) so we can attach our shared
+	// HTTP semaphore without mutating the struct they gave us.
+	opts := Options{}
+	if d.Options != nil {
+		opts = *d.Options
+	}
+	opts.sem = make(chan struct{}, d.maxConcurrency())
+
+	// Synchronize once here, across the whole manifest, rather than
+	// leaving each file's SaveWithContext call to wrap the callbacks
+	// under its own separate mutex: those calls run concurrently on
+	// this same shared opts, so without a single shared mutex two
+	// files could still invoke OnProgress (etc.) at once.
+	syncedOpts := synchronizeCallbacks(&opts)
+
+	fileSem := make(chan struct{}, d.maxConcurrentFiles())
+	group, groupCtx := errgroup.WithContext(ctx)
+	results := make([]Result, len(entries))
+
+	for i, entry := range entries {
+		// Once something's failed (or the caller cancelled), stop
+		// starting new files; whatever's already running is left to
+		// finish so its fileSem slot still gets released below.
+		if groupCtx.Err() != nil {
+			break
+		}
+
+		i, entry := i, entry
+		fileSem <- struct{}{}
+
+		group.Go(func() error {
+			defer func() { <-fileSem }()
+
+			u, err := url.Parse(entry.URL)
+			if err != nil {
+				return fmt.Errorf("manifest entry %d: %w", i, err)
+			}
+
+			threads := entry.Threads
+			if threads == 0 {
+				threads = 1
+			}
+
+			result, err := SaveWithContext(ctx, u, entry.Dest, threads, syncedOpts)
+			results[i] = result
+			return err
+		})
+	}
+
+	err := group.Wait()
+	return results, err
+}
+
+// LoadManifest reads a list of download entries from path. JSON files
+// (".json") are decoded as an array of ManifestEntry. Anything else
+// is treated as plain text, one entry per line, whitespace-separated:
+// "url destPath" or "url destPath threads". Blank lines and lines
+// starting with "#" are ignored.
+func LoadManifest(path string) ([]ManifestEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		var entries []ManifestEntry
+		if err := json.NewDecoder(f).Decode(&entries); err != nil {
+			return nil, fmt.Errorf("parsing manifest %q: %w", path, err)
+		}
+		return entries, nil
+	}
+
+	var entries []ManifestEntry
+	scanner := bufio.NewScanner(f)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("manifest %q line %d: expected \"url destPath [threads]\"", path, lineNum)
+		}
+
+		entry := ManifestEntry{URL: fields[0], Dest: fields[1]}
+		if len(fields) > 2 {
+			threads, err := strconv.ParseUint(fields[2], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("manifest %q line %d: invalid thread count %q", path, lineNum, fields[2])
+			}
+			entry.Threads = byte(threads)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
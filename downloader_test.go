@@ -0,0 +1,49 @@
+// DOWNLOADER_TEST.GO
+// Covers Downloader's error propagation: a failing entry shouldn't
+// lose the results already produced by its successful siblings.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package fasthttp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+// TestDownloadReportsFirstErrorButKeepsOtherResults checks that one
+// failing manifest entry surfaces as the returned error, while the
+// entries that did succeed still have their Result populated rather
+// than the whole batch coming back empty.
+func TestDownloadReportsFirstErrorButKeepsOtherResults(t *testing.T) {
+	body := []byte("ok")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(body)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	d := &Downloader{}
+
+	entries := []ManifestEntry{
+		{URL: srv.URL, Dest: filepath.Join(dir, "ok.bin")},
+		{URL: "http://127.0.0.1:0/unreachable", Dest: filepath.Join(dir, "bad.bin")},
+	}
+
+	results, err := d.Download(entries)
+	if err == nil {
+		t.Fatal("Download: expected an error from the unreachable entry, got nil")
+	}
+	if len(results) != len(entries) {
+		t.Fatalf("got %d results, want %d", len(results), len(entries))
+	}
+	if len(results[0].WorkerBytes) != 1 || results[0].WorkerBytes[0] != int64(len(body)) {
+		t.Fatalf("successful entry's result wasn't populated: %+v", results[0])
+	}
+}
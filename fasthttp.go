@@ -9,102 +9,361 @@
 package fasthttp
 
 import (
+	"context"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// DefaultMinChunkSize is how much of a resource GetReader's worker
+	// pool fetches per chunk, if Options.MinChunkSize isn't set.
+	DefaultMinChunkSize = 16 * 1024 * 1024
+
+	// DefaultMaxConcurrency is how many chunks GetReader will be
+	// fetching at once, if Options.MaxConcurrency isn't set. Chunks
+	// already fetched but not yet read stay buffered in memory and
+	// don't count against it.
+	DefaultMaxConcurrency = 4
 )
 
+// Options carries optional progress callbacks that callers can supply
+// to Get and Save in order to observe a download as it happens,
+// without needing to wrap the destination writer themselves.
+//
+// Any of the callbacks may be left nil; a nil callback is simply never
+// called.
+type Options struct {
+	// OnBeforeStart fires once, before any worker is spawned, with the
+	// total size of the resource (as reported by the content length)
+	// and the number of workers that will be used.
+	OnBeforeStart func(totalSize int64, workers int64)
+
+	// OnStart fires once per worker, when that worker's range request
+	// is about to begin, reporting the size of the chunk it owns.
+	OnStart func(workerID int, chunkSize int64)
+
+	// OnProgress fires every time a worker writes a buffer of bytes to
+	// the destination stream, reporting the number of bytes written in
+	// that call.
+	OnProgress func(workerID int, bytesWritten int64)
+
+	// OnFinish fires once per worker, when that worker's range request
+	// has completed (successfully or not).
+	OnFinish func(workerID int)
+
+	// MinChunkSize is the size, in bytes, of each chunk GetReader's
+	// worker pool fetches. Zero means DefaultMinChunkSize.
+	MinChunkSize int64
+
+	// MaxConcurrency bounds how many chunks GetReader will have in
+	// flight at once, decoupling parallelism from how large the
+	// resource is. Zero means DefaultMaxConcurrency.
+	MaxConcurrency int
+
+	// Expected, if set, is verified against the downloaded bytes once
+	// Save finishes; a mismatch is reported as an error and the file
+	// is left on disk for inspection.
+	Expected *Expected
+
+	// Resume, if true, lets Save pick up an interrupted download where
+	// it left off instead of re-fetching bytes already on disk. It
+	// tracks progress in a sidecar "<path>.fasthttp-state" file, and
+	// only resumes if the server's ETag or Last-Modified still match
+	// what that sidecar recorded; otherwise it starts over.
+	Resume bool
+
+	// Client configures the underlying HTTP behavior: custom headers,
+	// user agent, retry/backoff, and transport tuning. Nil means
+	// DefaultClient's defaults are used.
+	Client *Client
+
+	// sem, if set, bounds how many HTTP requests getRange will allow
+	// in flight at once across every caller sharing this Options
+	// value. It's unexported because it's wired up internally by
+	// Downloader, not something a caller sets directly.
+	sem chan struct{}
+
+	// synced records that this Options' callbacks have already been
+	// wrapped by synchronizeCallbacks, so a caller that flows through
+	// more than one entry point (Downloader calling into Save, for
+	// instance) doesn't get wrapped twice under two different
+	// mutexes.
+	synced bool
+}
+
+// minChunkSize returns the configured chunk size, or the default if
+// opts is nil or didn't set one.
+func (o *Options) minChunkSize() int64 {
+	if o == nil || o.MinChunkSize <= 0 {
+		return DefaultMinChunkSize
+	}
+	return o.MinChunkSize
+}
+
+// maxConcurrency returns the configured worker pool size, or the
+// default if opts is nil or didn't set one.
+func (o *Options) maxConcurrency() int {
+	if o == nil || o.MaxConcurrency <= 0 {
+		return DefaultMaxConcurrency
+	}
+	return o.MaxConcurrency
+}
+
+// synchronizeCallbacks returns an Options whose callbacks are safe to
+// call from multiple worker goroutines at once. download, getRange,
+// and their chunked/resumable variants all call a shared Options'
+// callbacks concurrently whenever more than one worker is in flight,
+// so without this a caller's OnProgress (say) would need to do its
+// own locking just to keep a running total, which defeats the point
+// of handing callbacks out as a building block. If opts is nil, or
+// was already synchronized upstream (e.g. by Downloader before it
+// fans out across files), it's returned as-is.
+func synchronizeCallbacks(opts *Options) *Options {
+	if opts == nil || opts.synced {
+		return opts
+	}
+
+	wrapped := *opts
+	wrapped.synced = true
+
+	var mu sync.Mutex
+
+	if fn := opts.OnBeforeStart; fn != nil {
+		wrapped.OnBeforeStart = func(totalSize int64, workers int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			fn(totalSize, workers)
+		}
+	}
+	if fn := opts.OnStart; fn != nil {
+		wrapped.OnStart = func(workerID int, chunkSize int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			fn(workerID, chunkSize)
+		}
+	}
+	if fn := opts.OnProgress; fn != nil {
+		wrapped.OnProgress = func(workerID int, bytesWritten int64) {
+			mu.Lock()
+			defer mu.Unlock()
+			fn(workerID, bytesWritten)
+		}
+	}
+	if fn := opts.OnFinish; fn != nil {
+		wrapped.OnFinish = func(workerID int) {
+			mu.Lock()
+			defer mu.Unlock()
+			fn(workerID)
+		}
+	}
+
+	return &wrapped
+}
+
+// Expected carries the digest a caller expects Save's downloaded
+// bytes to hash to, so Save can catch a corrupted or tampered
+// transfer instead of silently writing it to disk.
+type Expected struct {
+	// Algorithm is one of "sha256", "sha1", or "md5".
+	Algorithm string
+
+	// Digest is the expected digest, as a lowercase hex string.
+	Digest string
+}
+
+// Result describes the outcome of a completed Get or Save call: what
+// was fetched, how big it was, how long it took, and how much each
+// worker contributed. Callers building progress UIs or logging on top
+// of this library can use it to summarize a finished transfer.
+type Result struct {
+	// URL is the resource that was requested.
+	URL string
+
+	// TotalSize is the content length reported by the server, or zero
+	// if it was unknown.
+	TotalSize int64
+
+	// Elapsed is how long the download took, start to finish.
+	Elapsed time.Duration
+
+	// WorkerBytes holds the number of bytes written by each worker,
+	// indexed by worker ID.
+	WorkerBytes []int64
+
+	// File is the output file handle that was written to. It is nil
+	// for Get, which writes into an in-memory buffer instead.
+	File *os.File
+}
+
 // Perform a threaded HTTP GET on the input UEL, using the specified
 // number of threads, and return the reponse data as a slice of bytes.
 // Useful for embedding in an application workflow, but limited by the
 // valid size of a byte slice, and avilable system memory resources.
-func Get(u *url.URL, threads byte) ([]byte, error) {
+// opts may be nil if the caller doesn't need progress callbacks.
+func Get(u *url.URL, threads byte, opts *Options) ([]byte, Result, error) {
+	return GetWithContext(context.Background(), u, threads, opts)
+}
+
+// GetWithContext behaves like Get, but aborts as soon as ctx is
+// cancelled, interrupting every worker in flight rather than letting
+// them run to completion against a caller who has already given up.
+func GetWithContext(ctx context.Context, u *url.URL, threads byte, opts *Options) ([]byte, Result, error) {
+	start := time.Now()
+	opts = synchronizeCallbacks(opts)
+
 	// Calculate the content length
-	length, err := getContentLength(u)
+	length, err := getContentLength(ctx, u, opts)
 	if err != nil {
-		return nil, err
+		return nil, Result{}, err
 	}
-	
-	// Create the "file" we're going to catch the response into
+
+	// Create the "file" we're going to catch the response into. If we
+	// know the size up front we can allocate it exactly; otherwise
+	// bufferWriterAt grows the buffer itself as writes arrive.
 	var f bufferWriterAt
-	if(length == 0) {
-		// Okay, no size. So we create the highest reasonable capcity.
-		f.buffer = make([]byte, 0, math.MaxInt32)
-	} else {
-		// Better. Let's create a real-sized buffer.
+	if length > 0 {
 		f.buffer = make([]byte, length, length)
 	}
-	
+
 	// Actually perform the download.
-	err = download(&f, u, threads, length)
-	
-	return f.buffer, err
+	workerBytes, err := download(ctx, &f, u, threads, length, opts)
+
+	result := Result{
+		URL:         u.String(),
+		TotalSize:   length,
+		Elapsed:     time.Since(start),
+		WorkerBytes: workerBytes,
+	}
+
+	return f.buffer, result, err
 }
 
 // Perform a threaded HTTP GET on the input UEL, using the specified
 // number of threads, and save the results to a file at the specified
 // path. Allows large file downloads, without being limited by the
 // golang slice limitations, but necessitates use of a temp file if
-// the intended target is not a file.
-func Save(u *url.URL, path string, threads byte) error {
-	// Calculate the content length
-	length, err := getContentLength(u)
-	if err != nil {
-		return err
-	}
-	
+// the intended target is not a file. opts may be nil if the caller
+// doesn't need progress callbacks.
+func Save(u *url.URL, path string, threads byte, opts *Options) (Result, error) {
+	return SaveWithContext(context.Background(), u, path, threads, opts)
+}
+
+// SaveWithContext behaves like Save, but aborts as soon as ctx is
+// cancelled, interrupting every worker in flight rather than letting
+// them run to completion against a caller who has already given up.
+func SaveWithContext(ctx context.Context, u *url.URL, path string, threads byte, opts *Options) (Result, error) {
+	start := time.Now()
+	opts = synchronizeCallbacks(opts)
+
 	// Let's prepare a file to be written to. We'll need to create any
 	// parent directories first.
 	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
-		return err
+		return Result{}, err
 	}
-	
-	// Now we can actually open the file
-	f, err := os.Create(path)
-	if err != nil {
-		return err
+
+	var length int64
+	var workerBytes []int64
+	var f *os.File
+	var err error
+
+	if opts != nil && opts.Resume {
+		// Resuming re-uses (or creates) the destination file itself,
+		// so it manages opening it and reporting the content length.
+		f, length, workerBytes, err = saveResumable(ctx, u, path, opts)
+	} else {
+		length, err = getContentLength(ctx, u, opts)
+		if err == nil {
+			f, err = os.Create(path)
+		}
+		if err == nil {
+			workerBytes, err = download(ctx, f, u, threads, length, opts)
+		}
 	}
-	defer f.Close()
-	
-	// Actually perform the download.
-	err = download(f, u, threads, length)
-	return err
+	if f != nil {
+		defer f.Close()
+	}
+
+	if err == nil && opts != nil && opts.Expected != nil {
+		err = verifyDigest(path, opts.Expected)
+	}
+
+	result := Result{
+		URL:         u.String(),
+		TotalSize:   length,
+		Elapsed:     time.Since(start),
+		WorkerBytes: workerBytes,
+		File:        f,
+	}
+
+	return result, err
 }
 
 // Perform a head request to get the size, in bytes, of the resource.
-func getContentLength(u *url.URL) (int64, error) {
+func getContentLength(ctx context.Context, u *url.URL, opts *Options) (int64, error) {
+	info, err := headInfo(ctx, u, opts)
+	return info.Length, err
+}
+
+// resourceInfo is what a HEAD request tells us about a resource:
+// enough to decide whether ranged, resumable downloads are possible,
+// and whether a previous partial download still matches it.
+type resourceInfo struct {
+	Length       int64
+	ETag         string
+	LastModified string
+}
+
+// headInfo performs a head request and reports the resource's size
+// along with its ETag and Last-Modified, if the server sent them.
+// ctx is used both to cancel the request itself and, via Client.do's
+// attempt loop, to cut short any retry backoff.
+func headInfo(ctx context.Context, u *url.URL, opts *Options) (resourceInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", u.String(), nil)
+	if err != nil {
+		return resourceInfo{}, err
+	}
+
 	// Make the head request.
-	resp, err := http.Head(u.String())
+	resp, err := resolveClient(opts).do(req)
 	if err != nil {
-		return 0, err
+		return resourceInfo{}, err
 	}
 	resp.Body.Close()
-	
+
 	// Make sure the response is good.
 	if resp.StatusCode != 200 {
-		return 0, fmt.Errorf("bad response code: %d", resp.StatusCode)
+		return resourceInfo{}, fmt.Errorf("bad response code: %d", resp.StatusCode)
 	}
-	
+
+	info := resourceInfo{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
 	// If no range request is accepted, no point in going further
 	// Warning: According to the HTTP spec, a server doesn't *have* to
 	// report this. But we need to know for sure for the rest of the
 	// library to work properly.
 	if resp.Header.Get("Accept-Ranges") != "bytes" {
-		return 0, nil
+		return info, nil
 	}
-	
+
 	// If cntent length wasn't returned, no point in going further.
 	if resp.ContentLength < 0 {
-		return 0, nil
+		return info, nil
 	}
 
 	// Okay, let's just report what we got then.
-	return resp.ContentLength, nil
+	info.Length = resp.ContentLength
+	return info, nil
 }
 
 // This is a stream that targets a buffer (so that we can return a
@@ -117,9 +376,18 @@ type bufferWriterAt struct{
 // Our stream needs to export the WriteAt if it is going to satisfy
 // the io.WriterAt inetrface.
 func (w *bufferWriterAt) WriteAt(p []byte, off int64) (int, error) {
-	// First, make sure the requested fits within the slice.
-	if (off < 0) || (len(p) + int(off) > len(w.buffer)) {
-		w.buffer = w.buffer[0:len(p) + int(off)]
+	need := int(off) + len(p)
+
+	// First, make sure the requested write fits within the slice. If
+	// it doesn't even fit in the underlying array, grow the array
+	// (doubling, like append does) instead of assuming capacity was
+	// pre-allocated to the final size.
+	if need > cap(w.buffer) {
+		grown := make([]byte, need, 2*cap(w.buffer)+need)
+		copy(grown, w.buffer)
+		w.buffer = grown
+	} else if need > len(w.buffer) {
+		w.buffer = w.buffer[0:need]
 	}
 
 	// Do a simple copy loop, incrementing the offset as we go.
@@ -130,12 +398,17 @@ func (w *bufferWriterAt) WriteAt(p []byte, off int64) (int, error) {
 	return len(p), nil
 }
 
-func download(f io.WriterAt, u *url.URL, threads byte, length int64) error {
+// download fans the resource out across threads workers (each
+// responsible for a range of the resource) and returns, per worker,
+// how many bytes it wrote. opts may be nil. If any worker fails, or
+// ctx is cancelled, the rest of the workers are interrupted instead
+// of being left to run to completion.
+func download(ctx context.Context, f io.WriterAt, u *url.URL, threads byte, length int64, opts *Options) ([]int64, error) {
 	// They did specify to actually use threads, yes?
 	if threads == 0 {
-		return fmt.Errorf("cannot download \"%s\" download using %d threads", u.String(), threads)
+		return nil, fmt.Errorf("cannot download \"%s\" download using %d threads", u.String(), threads)
 	}
-	
+
 	// If the file size is smaller than the number of threads
 	// specified, then just use one. This will also handle cases where
 	// length is zero or unknown, or where range requests aren't
@@ -143,79 +416,121 @@ func download(f io.WriterAt, u *url.URL, threads byte, length int64) error {
 	if length < int64(threads) {
 		threads = 1
 	}
-	
+
+	workerBytes := make([]int64, threads)
+
+	if opts != nil && opts.OnBeforeStart != nil {
+		opts.OnBeforeStart(length, int64(threads))
+	}
+
 	// If we're only using one thread, then a range request isn't
 	// necessary.
 	if threads == 1 {
-		return getRange(f, u, 0, (-1))
+		err := getRange(ctx, f, u, 0, (-1), 0, opts, &workerBytes[0])
+		return workerBytes, err
 	}
-	
+
 	// Okay, this is our job. Now we need to find our transfer size per
 	// connection. This probably won't divide evenly, so we have to
 	// track the modulo also, so we can have the first connection grab
 	// the leftover bytes (which should be a very small number anyway.)
 	blocksize := length / int64(threads)
 	remainder := length % int64(threads)
-	
-	// It's spawing time. We'll need a channel and wait group to know
-	// if/when the transfers are done, and catch any errors that might
-	// have occuured.
-	errors := make(chan error)
-    var wg sync.WaitGroup
-	
+
+	// errgroup gives us both: a WaitGroup to know when every worker
+	// has returned, and a derived context that's cancelled the instant
+	// any one worker returns an error, so its siblings stop reading
+	// instead of running to completion on a transfer we already know
+	// failed.
+	group, groupCtx := errgroup.WithContext(ctx)
+
 	// We'll need to track our range offset as we spawn the
 	// downloaders.
 	offset := int64(0)
-	
+	workerID := 0
+
 	// Spawn each of the download threads, each having a specific
 	// range (that was caculated above.)
-	for offset < length {	
+	for offset < length {
 			start := offset
 			end := start + blocksize + remainder - 1
-			wg.Add(1)
-		    go func() {
-		        defer wg.Done()
-		        errors <- getRange(f, u, start, end)
-		    }()
+			id := workerID
+			group.Go(func() error {
+				return getRange(groupCtx, f, u, start, end, id, opts, &workerBytes[id])
+			})
 			offset = end + 1
 			remainder = 0
+			workerID++
 	}
-	
-	// We need to listen for errors.
-	var err error
-    go func() {
-        for e := range errors {
-            err = e
-        }
-    }()
-	
-	// Now we wait for the downloaders to exit and we know we're done
-	wg.Wait()
-	return err
+
+	// Now we wait for the downloaders to exit and we know we're done.
+	// group.Wait returns the first error reported by any worker, not
+	// just the last one that happened to assign to a shared variable.
+	err := group.Wait()
+	if err != nil && ctx.Err() == nil {
+		// At least one range worker failed (after the Client's own
+		// retries, where its error was retryable) and the rest were
+		// cancelled. That often means the server dropped range support
+		// partway through the transfer, so fall back to one plain,
+		// non-ranged GET instead of giving up.
+		fallbackBytes := make([]int64, 1)
+		if fallbackErr := getRange(ctx, f, u, 0, (-1), 0, opts, &fallbackBytes[0]); fallbackErr == nil {
+			return fallbackBytes, nil
+		}
+	}
+	return workerBytes, err
 }
 
-// Perform a range request, of zero-indexed bytes start through end. 
-// Write the response bytes into the corresponding offset of the 
+// Perform a range request, of zero-indexed bytes start through end.
+// Write the response bytes into the corresponding offset of the
 // WriteAt stream. *Special Case: If end is before start, don't
 // perform a range request. Just perform a standard get instead.
-func getRange(f io.WriterAt, u *url.URL, start int64, end int64) error {
+// workerID identifies this range for the opts callbacks, and
+// bytesWritten accumulates the total bytes this worker wrote so the
+// caller can report it back in a Result. ctx is checked between reads
+// so a cancellation (ours or a sibling worker's failure) interrupts
+// the transfer instead of running it to completion.
+func getRange(ctx context.Context, f io.WriterAt, u *url.URL, start int64, end int64, workerID int, opts *Options, bytesWritten *int64) error {
 	// Turn the request arguments into a proper HTTP request
-	req, _ := http.NewRequest("GET", u.String(), nil)
-	
+	req, _ := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+
 	// If the end is less than start, this is not a range request
 	if end >= start {
     	req.Header.Add("Range", fmt.Sprintf("bytes=%d-%d", start, end))
 	}
 
-	// Make the request.
-    var client http.Client
-    resp, err := client.Do(req)
+	if opts != nil && opts.OnStart != nil {
+		chunkSize := int64(-1)
+		if end >= start {
+			chunkSize = end - start + 1
+		}
+		opts.OnStart(workerID, chunkSize)
+	}
+	if opts != nil && opts.OnFinish != nil {
+		defer opts.OnFinish(workerID)
+	}
+
+	// If we're sharing a global request budget (set up by a
+	// Downloader), wait for a slot before actually making the
+	// request.
+	if opts != nil && opts.sem != nil {
+		select {
+		case opts.sem <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		defer func() { <-opts.sem }()
+	}
+
+	// Make the request, retrying per the resolved Client's policy on
+	// a 5xx or a network error (a timeout included).
+	resp, err := resolveClient(opts).do(req)
 	if err != nil {
 		return err
 	}
-	
+
 	defer resp.Body.Close()
-	
+
 	// Is the response good?
 	if end < start {
 		// This was NOT a range request.
@@ -228,23 +543,210 @@ func getRange(f io.WriterAt, u *url.URL, start int64, end int64) error {
 			return fmt.Errorf("bad response code: %d while reading bytes %d through %d", resp.StatusCode, start, end)
 		}
 	}
-  
+
 	// Setup buffers for data transfer. We're using a small buffer
 	// because we can't make assumptions about block size on the host,
 	// and we want the write loop to iterate as quickly as reasonable.
 	payload := make([]byte, 256)
 	var eof error
 	for eof == nil {
+		// Bail out early if we've been cancelled, rather than reading
+		// one more buffer just to throw it away.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Get the payload...
 		var bytes int
 		bytes, eof = resp.Body.Read(payload)
-		
+
 		// ... and write it to the stream.
 		f.WriteAt(payload[0:bytes], start)
-		
+
 		// Update our position.
 		start += int64(bytes)
+
+		// Report progress, then fold it into the worker's running
+		// total.
+		if bytes > 0 {
+			if opts != nil && opts.OnProgress != nil {
+				opts.OnProgress(workerID, int64(bytes))
+			}
+			*bytesWritten += int64(bytes)
+		}
+	}
+
+	return nil
+}
+
+// GetReader performs a threaded HTTP GET on the input URL, same as
+// Get, but instead of waiting for the whole resource and returning it
+// as a slice, it returns immediately with a reader that streams the
+// resource's bytes in order as chunks of it finish downloading. This
+// decouples parallelism from resource size: callers can start
+// processing bytes before the transfer completes, and no worker has
+// to hold the whole resource (or even its own whole range) in memory
+// at once. opts may be nil.
+func GetReader(u *url.URL, opts *Options) (io.ReadCloser, error) {
+	return GetReaderWithContext(context.Background(), u, opts)
+}
+
+// GetReaderWithContext behaves like GetReader, but the returned
+// reader's Close, or cancelling ctx, stops the worker pool instead of
+// letting it run to completion.
+func GetReaderWithContext(ctx context.Context, u *url.URL, opts *Options) (io.ReadCloser, error) {
+	opts = synchronizeCallbacks(opts)
+
+	length, err := getContentLength(ctx, u, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	// If we don't know the size (or the server won't do ranges),
+	// there's nothing to chunk: just stream the plain response body
+	// directly and let the caller read it as it arrives.
+	if length == 0 {
+		req, _ := http.NewRequestWithContext(ctx, "GET", u.String(), nil)
+		resp, err := resolveClient(opts).do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != 200 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("bad response code: %d", resp.StatusCode)
+		}
+		return resp.Body, nil
+	}
+
+	chunks := splitChunks(length, opts.minChunkSize())
+
+	ctx, cancel := context.WithCancel(ctx)
+	r := &chunkReader{
+		results: make([]chan chunkResult, len(chunks)),
+		cancel:  cancel,
+	}
+	for i := range r.results {
+		r.results[i] = make(chan chunkResult, 1)
+	}
+
+	go runChunkPipeline(ctx, u, chunks, opts, r.results)
+
+	return r, nil
+}
+
+// chunkRange identifies one piece of a resource to fetch: the
+// zero-indexed byte range [start, end], inclusive.
+type chunkRange struct {
+	start, end int64
+}
+
+// splitChunks divides a resource of the given length into consecutive
+// chunkRanges of at most chunkSize bytes each.
+func splitChunks(length int64, chunkSize int64) []chunkRange {
+	var chunks []chunkRange
+	for offset := int64(0); offset < length; offset += chunkSize {
+		end := offset + chunkSize - 1
+		if end > length-1 {
+			end = length - 1
+		}
+		chunks = append(chunks, chunkRange{start: offset, end: end})
+	}
+	return chunks
+}
+
+// chunkResult is what a worker reports back for a single chunk: its
+// bytes, or the error that prevented fetching them.
+type chunkResult struct {
+	data []byte
+	err  error
+}
+
+// offsetWriterAt rebases writes at an absolute offset starting at
+// base down to start at zero, so a WriterAt that only holds one piece
+// of a larger resource (runChunkPipeline's per-chunk buffer) can still
+// be handed to getRange, which writes at the absolute offset of the
+// range it fetched.
+type offsetWriterAt struct {
+	base int64
+	w    io.WriterAt
+}
+
+func (o offsetWriterAt) WriteAt(p []byte, off int64) (int, error) {
+	return o.w.WriteAt(p, off-o.base)
+}
+
+// runChunkPipeline fetches every chunk in order, using a worker pool
+// bounded by opts.maxConcurrency, and delivers each chunk's result on
+// its corresponding channel in results as soon as it's available.
+// Chunks aren't necessarily *fetched* in order, since several workers
+// run concurrently, but each chunk gets its own channel so chunkReader
+// can still consume them in order.
+func runChunkPipeline(ctx context.Context, u *url.URL, chunks []chunkRange, opts *Options, results []chan chunkResult) {
+	sem := make(chan struct{}, opts.maxConcurrency())
+	var wg sync.WaitGroup
+
+	for i, c := range chunks {
+		select {
+		case <-ctx.Done():
+			results[i] <- chunkResult{err: ctx.Err()}
+			continue
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(i int, c chunkRange) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			var buf bufferWriterAt
+			buf.buffer = make([]byte, c.end-c.start+1)
+			var written int64
+			// getRange writes at the absolute byte offset of the range
+			// it fetched, which is right when f is the whole
+			// destination (as in download()), but buf here only holds
+			// this one chunk: rebase writes to start at zero.
+			err := getRange(ctx, offsetWriterAt{base: c.start, w: &buf}, u, c.start, c.end, i, opts, &written)
+			results[i] <- chunkResult{data: buf.buffer, err: err}
+		}(i, c)
+	}
+
+	wg.Wait()
+}
+
+// chunkReader is the io.ReadCloser returned by GetReader. It consumes
+// chunks strictly in order: reading from chunk N+1 blocks until chunk
+// N has been fully delivered to the caller. A worker gives back its
+// pool slot as soon as its chunk is buffered on that chunk's channel,
+// not when the chunk is read, so a slow reader doesn't hold the pool
+// back: fetched chunks that haven't been read yet stay buffered in
+// memory, up to the whole resource.
+type chunkReader struct {
+	results []chan chunkResult
+	current int
+	pending []byte
+	cancel  context.CancelFunc
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 {
+		if r.current >= len(r.results) {
+			return 0, io.EOF
+		}
+
+		res := <-r.results[r.current]
+		r.current++
+		if res.err != nil {
+			return 0, res.err
+		}
+		r.pending = res.data
 	}
 
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+func (r *chunkReader) Close() error {
+	r.cancel()
 	return nil
 }
@@ -0,0 +1,128 @@
+// FASTHTTP_TEST.GO
+// Covers the callback serialization guarantee synchronizeCallbacks
+// provides: concurrent range workers must never invoke a caller's
+// callback at the same time, even when the caller does no locking of
+// its own (run with "go test -race" to catch a regression here).
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package fasthttp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// rangeTestServer serves body, supporting HEAD (reporting
+// Accept-Ranges and Content-Length) and ranged GET requests, so tests
+// can exercise the multi-worker download path.
+func rangeTestServer(t *testing.T, body []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			return
+		}
+
+		rng := r.Header.Get("Range")
+		if rng == "" {
+			w.Write(body)
+			return
+		}
+
+		var start, end int
+		if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil {
+			http.Error(w, "bad range", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(body[start : end+1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestGetCallbacksAreSerialized downloads with several worker threads
+// and an OnProgress callback that accumulates into a plain (unlocked)
+// int64, the same way the CLI's own progress bar does. Without
+// synchronizeCallbacks serializing the calls, this races.
+func TestGetCallbacksAreSerialized(t *testing.T) {
+	body := make([]byte, 256*1024)
+	for i := range body {
+		body[i] = byte(i)
+	}
+	srv := rangeTestServer(t, body)
+
+	u := mustParseURL(t, srv.URL)
+
+	var written int64
+	opts := &Options{
+		OnProgress: func(workerID int, n int64) {
+			written += n
+		},
+	}
+
+	data, _, err := Get(u, 8, opts)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(data) != len(body) {
+		t.Fatalf("got %d bytes, want %d", len(data), len(body))
+	}
+	if written != int64(len(body)) {
+		t.Fatalf("OnProgress saw %d bytes, want %d", written, len(body))
+	}
+}
+
+// TestGetWithContextCancelledBeforeHead checks that an already-
+// cancelled context makes GetWithContext return promptly, even
+// against a server that never answers the HEAD request at all.
+// getContentLength/headInfo used to build that HEAD request with
+// plain http.NewRequest, so it carried no context and this would
+// otherwise block until the server (or the test) gave up.
+func TestGetWithContextCancelledBeforeHead(t *testing.T) {
+	hang := make(chan struct{})
+	t.Cleanup(func() { close(hang) })
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer srv.Close()
+
+	u := mustParseURL(t, srv.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := GetWithContext(ctx, u, 1, nil)
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("GetWithContext: expected an error from the cancelled context, got nil")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("GetWithContext did not return within 2s of a cancelled context")
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", raw, err)
+	}
+	return u
+}
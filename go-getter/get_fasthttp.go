@@ -8,71 +8,159 @@ package getter
 
 import (
 	"net/url"
-	"regexp"
+	"path"
 	"strconv"
 	"strings"
-	
+
 	"github.com/jameswelch2-bydeluxe/fasthttp"
 )
 
-type FastHttpGetter struct{}
+type FastHttpGetter struct{
+	// Downloader bounds how many files this getter (and any directory
+	// it recurses into) will fetch at once, and how many HTTP requests
+	// it'll have in flight across all of them. It's created lazily
+	// with conservative defaults, and shared across recursive calls so
+	// the cap applies to the whole directory tree, not just one level
+	// of it.
+	Downloader *fasthttp.Downloader
 
-func (g *FastHttpGetter) Get(dst string, u *url.URL) error {
-	// Check the fragment of the URL. Was the number of connections
-	// specified? (That should be a byte value > 1.)
-	threads, err := strconv.ParseUint(u.Fragment, 10, 8);
-	if err != nil {
-		threads = 1
+	// MaxDepth bounds how many directory levels deep Get will recurse.
+	// Zero means unlimited.
+	MaxDepth int
+
+	// RespectRobotsTxt, if true, has Get check the host's robots.txt
+	// before recursing into each directory, and skip any directory
+	// whose path is disallowed for all crawlers. Files inside a listed
+	// directory aren't checked individually.
+	RespectRobotsTxt bool
+
+	robots map[string][]string
+}
+
+func (g *FastHttpGetter) downloader() *fasthttp.Downloader {
+	if g.Downloader == nil {
+		g.Downloader = &fasthttp.Downloader{}
+	}
+	return g.Downloader
+}
+
+// robotsDisallowed reports whether u's path is disallowed by its
+// host's robots.txt, fetching and caching that file the first time
+// each host is seen.
+func (g *FastHttpGetter) robotsDisallowed(u *url.URL) bool {
+	prefixes, ok := g.robots[u.Host]
+	if !ok {
+		prefixes = fetchRobotsDisallow(u)
+		if g.robots == nil {
+			g.robots = make(map[string][]string)
+		}
+		g.robots[u.Host] = prefixes
 	}
-	
-	// Is threads a byte value? If not, we need to ignoreit.
-	if threads > 255 {
+
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(u.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// threadsFromFragment reads a thread count out of a URL's fragment
+// ("#4" means 4 threads), falling back to 1 for anything missing or
+// out of range.
+func threadsFromFragment(u *url.URL) byte {
+	threads, err := strconv.ParseUint(u.Fragment, 10, 8)
+	if err != nil || threads == 0 || threads > 255 {
 		threads = 1
 	}
-	
+	return byte(threads)
+}
+
+func (g *FastHttpGetter) Get(dst string, u *url.URL) error {
+	// seen is scoped to this one Get call, not to the getter itself: a
+	// FastHttpGetter is meant to be created once and reused across
+	// many unrelated Get calls (that's the whole point of caching
+	// Downloader and robots.txt on it), so a getter-lifetime seen
+	// map would let a second, unrelated Get for the same source URL
+	// but a different destination silently skip files it had already
+	// "seen" on the first call, leaving that destination incomplete.
+	seen := make(map[string]struct{})
+	return g.getAtDepth(dst, u, 0, seen)
+}
+
+func (g *FastHttpGetter) getAtDepth(dst string, u *url.URL, depth int, seen map[string]struct{}) error {
 	// Does the dst end with a "/"? If not, we need to add it.
 	if !strings.HasSuffix(dst, "/") {
 		dst += "/"
 	}
-	
+
 	// Is the URL a directory path? If not, just pass this along to
 	// GetFile.
 	if !strings.HasSuffix(u.Path, "/") {
-		return g.GetFile(dst + u.Path[strings.LastIndex(u.Path, "/") + 1:len(u.Path)], u)
+		return g.GetFile(dst + path.Base(u.Path), u)
+	}
+
+	if g.MaxDepth > 0 && depth >= g.MaxDepth {
+		return nil
 	}
-	
+
+	if g.RespectRobotsTxt && g.robotsDisallowed(u) {
+		return nil
+	}
+
 	// Okay, let's get the directory listing, then
-	index, _ := fasthttp.Get(u, byte(threads))
+	index, _, err := fasthttp.Get(u, threadsFromFragment(u), nil)
+	if err != nil {
+		return err
+	}
+
+	links, err := extractLinks(index, u)
 	if err != nil {
 		return err
 	}
-	
-	// Now let's fiter out the hrefs...
-	re := regexp.MustCompile("\\shref=\\\".+\\\"")
-	hrefs := re.FindAllString(string(index), -1)
-	
-	// ...then interate them to dispatch the downloads.
-	for _, href := range hrefs {
-		// Trim off the leftovers from the regular expression search.
-		href = href[7:len(href) - 1]
-		
-		// Only if its a relative URL do we work with it.
-		if (!strings.Contains(href, ":")) && (!strings.HasPrefix(href, "/")) {
-			// Copy our base URL so we can append the href to it.
-			v := *u
-			v.Path += href
-			
-			// Is this a directory? If so, we need to add it to the
-			// destinaton path.
-			if strings.HasSuffix(href, "/") {
-				dst += href
-			}
-			
-			// Recurse into this method
-			err = g.Get(dst, &v)
-			if err != nil {
-				return err	
-			}
+
+	// ...sorting them into files (which we'll hand to the Downloader
+	// as a single bounded batch) and subdirectories (which we recurse
+	// into one at a time, since each needs its own listing fetched
+	// first). Each child's destination is computed independently, from
+	// the part of its path beyond this listing's, rather than by
+	// accumulating onto a shared variable across siblings.
+	var files []fasthttp.ManifestEntry
+	var dirs []*url.URL
+
+	for _, link := range links {
+		key := link.String()
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		rel := strings.TrimPrefix(link.Path, u.Path)
+		childDst := dst + rel
+
+		if strings.HasSuffix(link.Path, "/") {
+			dirs = append(dirs, link)
+		} else {
+			files = append(files, fasthttp.ManifestEntry{
+				URL:     link.String(),
+				Dest:    childDst,
+				Threads: threadsFromFragment(link),
+			})
+		}
+	}
+
+	// Fetch every file in this listing as one bounded batch, instead
+	// of spawning a goroutine (or opening a connection) per href with
+	// no limit on how many run at once.
+	if _, err := g.downloader().Download(files); err != nil {
+		return err
+	}
+
+	// Now recurse into each subdirectory we found.
+	for _, link := range dirs {
+		rel := strings.TrimPrefix(link.Path, u.Path)
+		if err := g.getAtDepth(dst+rel, link, depth+1, seen); err != nil {
+			return err
 		}
 	}
 
@@ -81,18 +169,7 @@ func (g *FastHttpGetter) Get(dst string, u *url.URL) error {
 }
 
 func (g *FastHttpGetter) GetFile(dst string, u *url.URL) error {
-	// Check the fragment of the URL. Was the number of connections
-	// specified? (That should be a byte value > 1.)
-	threads, err := strconv.ParseUint(u.Fragment, 10, 8);
-	if err != nil {
-		threads = 1
-	}
-	
-	// Is threads a byte value? If not, we need to ignoreit.
-	if threads > 255 {
-		threads = 1
-	}
-	
 	// Okay, now actually make the call to the library.
-	return fasthttp.Save(u, dst, byte(threads))
-}
\ No newline at end of file
+	_, err := fasthttp.Save(u, dst, threadsFromFragment(u), nil)
+	return err
+}
@@ -0,0 +1,87 @@
+// LINKS.GO
+// Extracts the links worth following out of an HTML directory listing.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package getter
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// extractLinks walks an HTML index page with a proper tokenizer
+// (rather than a regex, which is greedy, misses single-quoted and
+// unquoted hrefs, and mis-parses multiple hrefs on one line) and
+// returns every <a href="..."> resolved against base, skipping
+// anything that's not worth recursing into: same-page fragments,
+// query strings, and parent links that would walk back out of the
+// tree we're mirroring.
+func extractLinks(body []byte, base *url.URL) ([]*url.URL, error) {
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+
+	var links []*url.URL
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if err := tokenizer.Err(); err != io.EOF {
+				return links, err
+			}
+			return links, nil
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "a" {
+				continue
+			}
+			for _, attr := range token.Attr {
+				if attr.Key != "href" {
+					continue
+				}
+				if link, ok := resolveHref(base, attr.Val); ok {
+					links = append(links, link)
+				}
+			}
+		}
+	}
+}
+
+// resolveHref resolves href against base the same way a browser
+// would, then reports whether it's a link worth recursing into.
+func resolveHref(base *url.URL, href string) (*url.URL, bool) {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return nil, false
+	}
+
+	ref, err := url.Parse(href)
+	if err != nil {
+		return nil, false
+	}
+	resolved := base.ResolveReference(ref)
+
+	// A bare fragment ("#section") just points back at this same
+	// page.
+	if ref.Fragment != "" && ref.Path == "" && ref.RawQuery == "" {
+		return nil, false
+	}
+
+	// Links carrying a query string aren't a distinct resource to
+	// mirror; they're the same resource with different parameters.
+	if resolved.RawQuery != "" {
+		return nil, false
+	}
+
+	// "../" (and anything else that walks back above base) would
+	// recurse out of the tree we're mirroring and risk a cycle.
+	if !strings.HasPrefix(resolved.Path, base.Path) {
+		return nil, false
+	}
+
+	return resolved, true
+}
@@ -0,0 +1,93 @@
+// LINKS_TEST.GO
+// Covers extractLinks' HTML parsing and resolveHref's filtering: the
+// href quoting styles a tokenizer (rather than a regex) should get
+// right, and which kinds of links aren't worth recursing into.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package getter
+
+import (
+	"net/url"
+	"testing"
+)
+
+func mustParseBase(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("parsing %q: %v", raw, err)
+	}
+	return u
+}
+
+// TestExtractLinksQuotingStyles checks that single-quoted, unquoted,
+// and multiple hrefs on one line are all found, the way a regex-based
+// extractor tends to miss.
+func TestExtractLinksQuotingStyles(t *testing.T) {
+	base := mustParseBase(t, "http://example.com/dir/")
+	body := []byte(`<html><body>
+<a href="double.txt">double</a>
+<a href='single.txt'>single</a>
+<a href=unquoted.txt>unquoted</a> <a href="second.txt">second on same line</a>
+</body></html>`)
+
+	links, err := extractLinks(body, base)
+	if err != nil {
+		t.Fatalf("extractLinks: %v", err)
+	}
+
+	want := map[string]bool{
+		"http://example.com/dir/double.txt":   false,
+		"http://example.com/dir/single.txt":   false,
+		"http://example.com/dir/unquoted.txt": false,
+		"http://example.com/dir/second.txt":   false,
+	}
+	for _, l := range links {
+		if _, ok := want[l.String()]; !ok {
+			t.Errorf("unexpected link: %s", l)
+			continue
+		}
+		want[l.String()] = true
+	}
+	for href, seen := range want {
+		if !seen {
+			t.Errorf("missing expected link: %s", href)
+		}
+	}
+}
+
+// TestResolveHrefFiltersUnwantedLinks checks the cases resolveHref is
+// meant to skip: a bare same-page fragment, a link carrying a query
+// string, and a "../" that would walk back out of the directory
+// being mirrored.
+func TestResolveHrefFiltersUnwantedLinks(t *testing.T) {
+	base := mustParseBase(t, "http://example.com/dir/")
+
+	cases := []struct {
+		href    string
+		wantOK  bool
+		wantURL string
+	}{
+		{href: "file.txt", wantOK: true, wantURL: "http://example.com/dir/file.txt"},
+		{href: "#section", wantOK: false},
+		{href: "file.txt?query=1", wantOK: false},
+		{href: "../", wantOK: false},
+		{href: "../../etc/passwd", wantOK: false},
+		{href: "sub/file.txt", wantOK: true, wantURL: "http://example.com/dir/sub/file.txt"},
+		{href: "  spaced.txt  ", wantOK: true, wantURL: "http://example.com/dir/spaced.txt"},
+		{href: "", wantOK: false},
+	}
+
+	for _, c := range cases {
+		got, ok := resolveHref(base, c.href)
+		if ok != c.wantOK {
+			t.Errorf("resolveHref(%q): ok = %v, want %v", c.href, ok, c.wantOK)
+			continue
+		}
+		if ok && got.String() != c.wantURL {
+			t.Errorf("resolveHref(%q) = %s, want %s", c.href, got, c.wantURL)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// ROBOTS.GO
+// A minimal robots.txt check: enough to let FastHttpGetter skip paths
+// a site has asked crawlers to stay out of while mirroring a
+// directory listing.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package getter
+
+import (
+	"bufio"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// fetchRobotsDisallow fetches "/robots.txt" from u's host and returns
+// the Disallow path prefixes listed under the "User-agent: *" block.
+// Any failure (network error, missing file, a server that doesn't
+// bother with one) is treated as "no restrictions", since robots.txt
+// is advisory and frequently just absent.
+func fetchRobotsDisallow(u *url.URL) []string {
+	robotsURL := &url.URL{Scheme: u.Scheme, Host: u.Host, Path: "/robots.txt"}
+
+	resp, err := http.Get(robotsURL.String())
+	if err != nil {
+		return nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil
+	}
+
+	var disallow []string
+	appliesToUs := false
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:idx]))
+		value := strings.TrimSpace(line[idx+1:])
+
+		switch key {
+		case "user-agent":
+			appliesToUs = (value == "*")
+		case "disallow":
+			if appliesToUs && value != "" {
+				disallow = append(disallow, value)
+			}
+		}
+	}
+
+	return disallow
+}
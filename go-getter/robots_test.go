@@ -0,0 +1,78 @@
+// ROBOTS_TEST.GO
+// Covers fetchRobotsDisallow's parsing: picking out the "User-agent:
+// *" block, ignoring disallows scoped to other agents, and treating
+// a missing robots.txt as no restrictions.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package getter
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func robotsServer(t *testing.T, body string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestFetchRobotsDisallowAppliesOnlyToWildcardAgent checks that
+// Disallow lines are only picked up from the "User-agent: *" block,
+// and that a block scoped to some other crawler is ignored.
+func TestFetchRobotsDisallowAppliesOnlyToWildcardAgent(t *testing.T) {
+	srv := robotsServer(t, `# comment, should be skipped
+User-agent: SomeOtherBot
+Disallow: /only-for-other-bot/
+
+User-agent: *
+Disallow: /private/
+Disallow: /tmp/
+`)
+
+	u := mustParseBase(t, srv.URL+"/dir/")
+	got := fetchRobotsDisallow(u)
+
+	want := map[string]bool{"/private/": false, "/tmp/": false}
+	if len(got) != len(want) {
+		t.Fatalf("fetchRobotsDisallow = %v, want entries for %v", got, want)
+	}
+	for _, prefix := range got {
+		if _, ok := want[prefix]; !ok {
+			t.Errorf("unexpected disallow prefix: %s", prefix)
+			continue
+		}
+		want[prefix] = true
+	}
+	for prefix, seen := range want {
+		if !seen {
+			t.Errorf("missing expected disallow prefix: %s", prefix)
+		}
+	}
+}
+
+// TestFetchRobotsDisallowMissingFileMeansNoRestrictions checks that a
+// 404 (or any non-200) for /robots.txt is treated as "nothing
+// disallowed" rather than an error, since robots.txt is advisory and
+// frequently just absent.
+func TestFetchRobotsDisallowMissingFileMeansNoRestrictions(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	u := mustParseBase(t, srv.URL+"/dir/")
+	if got := fetchRobotsDisallow(u); got != nil {
+		t.Fatalf("fetchRobotsDisallow = %v, want nil", got)
+	}
+}
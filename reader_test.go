@@ -0,0 +1,46 @@
+// READER_TEST.GO
+// Covers GetReader's chunked streaming pipeline: chunks are fetched
+// out of order by a worker pool, but must still be delivered to the
+// reader strictly in order.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package fasthttp
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// TestGetReaderStreamsChunksInOrder forces several small chunks (via
+// a tiny MinChunkSize) across a handful of concurrent workers, and
+// checks the bytes read back out match the original content exactly.
+func TestGetReaderStreamsChunksInOrder(t *testing.T) {
+	body := make([]byte, 10*64*1024+37)
+	for i := range body {
+		body[i] = byte(i % 251)
+	}
+	srv := rangeTestServer(t, body)
+	u := mustParseURL(t, srv.URL)
+
+	opts := &Options{
+		MinChunkSize:   64 * 1024,
+		MaxConcurrency: 4,
+	}
+
+	r, err := GetReader(u, opts)
+	if err != nil {
+		t.Fatalf("GetReader: %v", err)
+	}
+	defer r.Close()
+
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading: %v", err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Fatalf("got %d bytes, want %d; content mismatch", len(got), len(body))
+	}
+}
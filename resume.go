@@ -0,0 +1,200 @@
+// RESUME.GO
+// Lets Save pick up an interrupted download where it left off,
+// instead of re-fetching bytes that already landed on disk, by
+// tracking completed chunks in a sidecar state file.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package fasthttp
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"os"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// resumeState is the sidecar "<path>.fasthttp-state" file's contents:
+// enough to tell whether a previous partial download still matches
+// the resource being fetched, and which of its chunks already landed.
+type resumeState struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Length       int64  `json:"length"`
+	ChunkSize    int64  `json:"chunkSize"`
+	Done         []bool `json:"done"`
+}
+
+// stateName returns the sidecar path for a given destination path.
+func stateName(path string) string {
+	return path + ".fasthttp-state"
+}
+
+// loadResumeState reads a sidecar file, if one exists. A missing
+// sidecar isn't an error; it just means there's nothing to resume.
+func loadResumeState(path string) (resumeState, bool) {
+	data, err := os.ReadFile(stateName(path))
+	if err != nil {
+		return resumeState{}, false
+	}
+
+	var state resumeState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return resumeState{}, false
+	}
+
+	return state, true
+}
+
+// saveResumeState writes the sidecar file. Progress is saved eagerly
+// (after every chunk) rather than buffered in memory, so a process
+// that's killed mid-download still leaves an accurate record of what
+// it finished.
+func saveResumeState(path string, state resumeState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(stateName(path), data, 0644)
+}
+
+// doneSize returns the smallest file size consistent with done: the
+// byte just past the end of the last chunk marked done, or zero if
+// none are.
+func doneSize(chunks []chunkRange, done []bool) int64 {
+	var size int64
+	for i, c := range chunks {
+		if i < len(done) && done[i] && c.end+1 > size {
+			size = c.end + 1
+		}
+	}
+	return size
+}
+
+// saveResumable performs a resumable, chunked download of u into
+// path. If a sidecar state file from a previous attempt exists and
+// still matches what the server reports (same ETag or Last-Modified,
+// same length and chunk size), only the chunks it hadn't finished are
+// fetched; otherwise the download starts over from scratch. On full
+// success the sidecar is removed.
+func saveResumable(ctx context.Context, u *url.URL, path string, opts *Options) (*os.File, int64, []int64, error) {
+	info, err := headInfo(ctx, u, opts)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	// If the server didn't advertise ranges, or didn't report a
+	// length, there's nothing to chunk or resume: fall back to one
+	// plain GET, the same way download() does for an unranged
+	// request. Resuming a sidecar from a previous, ranged attempt
+	// against the same path wouldn't make sense here either, so it's
+	// left alone rather than removed.
+	if info.Length <= 0 {
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		workerBytes := make([]int64, 1)
+		if err := getRange(ctx, f, u, 0, (-1), 0, opts, &workerBytes[0]); err != nil {
+			f.Close()
+			return nil, info.Length, workerBytes, err
+		}
+		return f, info.Length, workerBytes, nil
+	}
+
+	chunkSize := opts.minChunkSize()
+	chunks := splitChunks(info.Length, chunkSize)
+
+	state, ok := loadResumeState(path)
+	matches := ok &&
+		state.Length == info.Length &&
+		state.ChunkSize == chunkSize &&
+		len(state.Done) == len(chunks) &&
+		((info.ETag != "" && state.ETag == info.ETag) ||
+			(info.LastModified != "" && state.LastModified == info.LastModified))
+
+	// The sidecar can outlive the file it describes: it's written
+	// (and left behind on failure) separately from path itself, which
+	// could since have been deleted or replaced by something else
+	// entirely. Trusting Done in that case would skip chunks that
+	// were never actually written to this file, silently truncating
+	// or corrupting it. So before trusting it, make sure the file on
+	// disk is at least as large as the Done flags claim.
+	if matches {
+		if fi, statErr := os.Stat(path); statErr != nil || fi.Size() < doneSize(chunks, state.Done) {
+			matches = false
+		}
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY
+	if !matches {
+		// Either there's nothing to resume, or what's there doesn't
+		// match the resource anymore: start clean.
+		state = resumeState{
+			ETag:         info.ETag,
+			LastModified: info.LastModified,
+			Length:       info.Length,
+			ChunkSize:    chunkSize,
+			Done:         make([]bool, len(chunks)),
+		}
+		flags |= os.O_TRUNC
+	}
+
+	f, err := os.OpenFile(path, flags, 0644)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+
+	var stateMu sync.Mutex
+	// One slot per chunk, not per pool slot: several chunks can be in
+	// flight under the same pool slot over the life of the download,
+	// and they'd otherwise race writing to a shared counter.
+	workerBytes := make([]int64, len(chunks))
+
+	group, groupCtx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, opts.maxConcurrency())
+
+	for i, c := range chunks {
+		if state.Done[i] {
+			continue
+		}
+		// Once something's failed (or the caller cancelled), stop
+		// starting new chunks; whatever's already in flight is
+		// interrupted through groupCtx.
+		if groupCtx.Err() != nil {
+			break
+		}
+
+		i, c := i, c
+		sem <- struct{}{}
+
+		group.Go(func() error {
+			defer func() { <-sem }()
+
+			if err := getRange(groupCtx, f, u, c.start, c.end, i, opts, &workerBytes[i]); err != nil {
+				return err
+			}
+
+			stateMu.Lock()
+			state.Done[i] = true
+			err := saveResumeState(path, state)
+			stateMu.Unlock()
+			return err
+		})
+	}
+
+	err = group.Wait()
+	if err != nil {
+		f.Close()
+		return nil, info.Length, workerBytes, err
+	}
+
+	// Every chunk landed; the sidecar has done its job.
+	os.Remove(stateName(path))
+
+	return f, info.Length, workerBytes, nil
+}
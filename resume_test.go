@@ -0,0 +1,141 @@
+// RESUME_TEST.GO
+// Covers saveResumable's two silent-corruption cases: a server that
+// won't do ranges, and a sidecar state file left behind by a deleted
+// or replaced destination.
+//
+// Copyright 2016 Deluxe Media
+// Author: James Welch
+
+package fasthttp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// noRangeTestServer serves body but never advertises Accept-Ranges,
+// the way a plain static file server or CDN in front of one often
+// doesn't.
+func noRangeTestServer(t *testing.T, body []byte) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// etagRangeTestServer behaves like rangeTestServer, but also reports
+// a fixed ETag, so tests can build a sidecar state file that appears
+// to match it.
+func etagRangeTestServer(t *testing.T, body []byte, etag string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		if r.Method == http.MethodHead {
+			w.Header().Set("Accept-Ranges", "bytes")
+			w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+			return
+		}
+
+		rng := r.Header.Get("Range")
+		if rng == "" {
+			w.Write(body)
+			return
+		}
+
+		var start, end int
+		if _, err := fmt.Sscanf(rng, "bytes=%d-%d", &start, &end); err != nil {
+			http.Error(w, "bad range", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(body[start : end+1])
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// TestSaveResumableNoRangeFallback checks that a server without
+// Accept-Ranges (so headInfo reports Length == 0) still produces the
+// full file, instead of the zero chunks splitChunks(0, ...) would
+// otherwise hand back.
+func TestSaveResumableNoRangeFallback(t *testing.T) {
+	body := []byte("no ranges here, just the whole thing")
+	srv := noRangeTestServer(t, body)
+	u := mustParseURL(t, srv.URL)
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "out.bin")
+
+	f, _, _, err := saveResumable(context.Background(), u, dest, &Options{})
+	if err != nil {
+		t.Fatalf("saveResumable: %v", err)
+	}
+	f.Close()
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("reading %s: %v", dest, err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Fatalf("got %q, want %q", got, body)
+	}
+}
+
+// TestSaveResumableStaleStateInvalidated builds a sidecar claiming
+// every chunk is already done (matching ETag, length, and chunk
+// size), but deletes the destination file it describes first. If
+// saveResumable trusted Done without checking, it would write nothing
+// and report success on an empty/missing file.
+func TestSaveResumableStaleStateInvalidated(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 3*64*1024+10)
+	const etag = `"fixed-etag"`
+	srv := etagRangeTestServer(t, body, etag)
+	u := mustParseURL(t, srv.URL)
+
+	dir := t.TempDir()
+	dest := filepath.Join(dir, "out.bin")
+
+	chunkSize := int64(64 * 1024)
+	chunks := splitChunks(int64(len(body)), chunkSize)
+	state := resumeState{
+		ETag:      etag,
+		Length:    int64(len(body)),
+		ChunkSize: chunkSize,
+		Done:      make([]bool, len(chunks)),
+	}
+	for i := range state.Done {
+		state.Done[i] = true
+	}
+	if err := saveResumeState(dest, state); err != nil {
+		t.Fatalf("saveResumeState: %v", err)
+	}
+	// No destination file on disk at all: the stalest possible case.
+
+	opts := &Options{MinChunkSize: chunkSize}
+	f, _, _, err := saveResumable(context.Background(), u, dest, opts)
+	if err != nil {
+		t.Fatalf("saveResumable: %v", err)
+	}
+	f.Close()
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatalf("reading %s: %v", dest, err)
+	}
+	if !bytes.Equal(got, body) {
+		t.Fatalf("got %d bytes, want %d; stale state wasn't invalidated", len(got), len(body))
+	}
+}